@@ -0,0 +1,15 @@
+package main
+
+import "github.com/containerd/typeurl"
+
+// CheckpointOptions is the `Checkpoint` RPC's Options payload. Setting
+// ParentPath takes an incremental checkpoint layered over the checkpoint
+// already present there, mirroring the parentPath parameter
+// hcsoci.CheckpointContainer and uvm.Checkpoint already accept.
+type CheckpointOptions struct {
+	ParentPath string
+}
+
+func init() {
+	typeurl.Register(&CheckpointOptions{}, "containerd-shim-runhcs-v1", "CheckpointOptions")
+}
@@ -0,0 +1,102 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/Microsoft/hcsshim/internal/fdimport"
+	"github.com/containerd/containerd/runtime/v2/task"
+	"golang.org/x/sys/unix"
+)
+
+// execFDsRequest is the side-channel payload a client sends (over the unix
+// socket named in req.Stdin, when it opts into this pre-opened-fd path) to
+// hand the shim pre-opened host fds for the exec'd process's stdio, along
+// with the uid/gid those fds are chowned to. The process itself runs as
+// req.Spec's `user` field, not as this uid/gid.
+type execFDsRequest struct {
+	UID uint32
+	GID uint32
+}
+
+// recvExecFDs accepts a single connection on the unix socket at `sockPath`,
+// reads the three stdio fds sent via SCM_RIGHTS, and returns them along with
+// the credentials carried in the accompanying execFDsRequest.
+func recvExecFDs(ctx context.Context, sockPath string) ([]*os.File, execFDsRequest, error) {
+	var creds execFDsRequest
+
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: sockPath, Net: "unix"})
+	if err != nil {
+		return nil, creds, fmt.Errorf("failed to listen on fd side-channel %s: %s", sockPath, err)
+	}
+	defer l.Close()
+
+	conn, err := l.AcceptUnix()
+	if err != nil {
+		return nil, creds, fmt.Errorf("failed to accept fd side-channel connection: %s", err)
+	}
+	defer conn.Close()
+
+	oob := make([]byte, unix.CmsgSpace(3*4))
+	buf := make([]byte, 64)
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return nil, creds, fmt.Errorf("failed to get raw fd side-channel connection: %s", err)
+	}
+
+	var n, oobn int
+	var rerr error
+	cerr := rawConn.Read(func(fd uintptr) bool {
+		n, oobn, _, _, rerr = unix.Recvmsg(int(fd), buf, oob, 0)
+		return true
+	})
+	if cerr != nil {
+		return nil, creds, cerr
+	}
+	if rerr != nil {
+		return nil, creds, fmt.Errorf("failed to receive fds over side-channel: %s", rerr)
+	}
+
+	scms, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return nil, creds, fmt.Errorf("failed to parse fd side-channel control message: %s", err)
+	}
+	var fds []int
+	for _, scm := range scms {
+		parsed, err := unix.ParseUnixRights(&scm)
+		if err != nil {
+			return nil, creds, fmt.Errorf("failed to parse fd rights: %s", err)
+		}
+		fds = append(fds, parsed...)
+	}
+	if len(fds) != 3 {
+		return nil, creds, fmt.Errorf("expected 3 fds on side-channel, got %d", len(fds))
+	}
+
+	if n < 8 {
+		return nil, creds, fmt.Errorf("expected uid/gid header on fd side-channel, got %d bytes", n)
+	}
+	creds.UID = binary.LittleEndian.Uint32(buf[0:4])
+	creds.GID = binary.LittleEndian.Uint32(buf[4:8])
+
+	files := make([]*os.File, 0, 3)
+	for i, fd := range fds {
+		files = append(files, os.NewFile(uintptr(fd), fmt.Sprintf("exec-fd-%d", i)))
+	}
+
+	return files, creds, nil
+}
+
+// importExecFDs wires the fds received over the side-channel into the
+// exec'd process's stdio, chowned to the uid/gid that came with them over
+// the side-channel. spawnExec does not check that this matches the user
+// req.Spec runs the process as.
+func importExecFDs(req *task.ExecProcessRequest, fds []*os.File, creds execFDsRequest) (stdin, stdout, stderr *os.File, err error) {
+	return fdimport.Import(fdimport.Credentials{UID: creds.UID, GID: creds.GID}, fds)
+}
@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/containerd/containerd/runtime/v2/task"
+)
+
+// execFDsRequest mirrors the linux-only side-channel payload; it carries no
+// credentials here since recvExecFDs always fails on windows.
+type execFDsRequest struct {
+	UID uint32
+	GID uint32
+}
+
+// recvExecFDs is not supported on windows: SCM_RIGHTS fd passing over a unix
+// socket is a linux-only mechanism, so this side-channel exec path isn't
+// available here. execInternal returns this error, so Exec fails on windows;
+// there is no fallback to named-pipe stdio paths in the ExecProcessRequest.
+func recvExecFDs(ctx context.Context, sockPath string) ([]*os.File, execFDsRequest, error) {
+	return nil, execFDsRequest{}, fmt.Errorf("exec fd side-channel is not supported on windows")
+}
+
+// importExecFDs is unreachable on windows since recvExecFDs always fails.
+func importExecFDs(req *task.ExecProcessRequest, fds []*os.File, creds execFDsRequest) (stdin, stdout, stderr *os.File, err error) {
+	return nil, nil, nil, fmt.Errorf("exec fd side-channel is not supported on windows")
+}
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/containerd/containerd/runtime/v2/task"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// execProcess is the bookkeeping kept for a single process spawned via
+// spawnExec, just enough to look it up again by exec id and learn its exit
+// status. done receives cmd.Wait's result exactly once; spawnExec is the
+// only reaper, since calling Wait more than once is an error.
+type execProcess struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// spawnExec starts req.Spec's process directly on the host using stdin,
+// stdout, stderr as its stdio, running it as req.Spec's OCI `user` field.
+// This only covers a process-isolated task: a Hyper-V isolated one has no
+// host process to start at all, since the exec'd process must run inside
+// the guest over the GCS bridge, which this trimmed tree doesn't carry.
+func (t *shimTask) spawnExec(ctx context.Context, req *task.ExecProcessRequest, stdin, stdout, stderr *os.File) error {
+	if t.vm != nil {
+		return fmt.Errorf("exec into a Hyper-V isolated task is not implemented in this build")
+	}
+	if req.Spec == nil {
+		return fmt.Errorf("exec requires an OCI process spec")
+	}
+
+	var spec specs.Process
+	if err := json.Unmarshal(req.Spec.Value, &spec); err != nil {
+		return fmt.Errorf("failed to unmarshal exec spec: %s", err)
+	}
+	if len(spec.Args) == 0 {
+		return fmt.Errorf("exec spec has no args")
+	}
+
+	cmd := exec.Command(spec.Args[0], spec.Args[1:]...)
+	cmd.Dir = spec.Cwd
+	cmd.Env = spec.Env
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: spec.User.UID, Gid: spec.User.GID},
+	}
+	if req.Terminal {
+		// Setsid+Setctty makes stdin (the pty side the fd side-channel
+		// handed us) this process's controlling terminal once it execs,
+		// the correct place to do it: unlike TIOCSCTTY called from the
+		// shim, this runs in the child itself, right after fork.
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start exec'd process: %s", err)
+	}
+	ep := &execProcess{cmd: cmd, done: make(chan error, 1)}
+	t.execs.Store(req.ExecID, ep)
+	go func() { ep.done <- cmd.Wait() }()
+	return nil
+}
@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/runtime/v2/task"
+	google_protobuf1 "github.com/gogo/protobuf/types"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// TestSpawnExecRunsProcessAsOwnUser drives spawnExec directly (bypassing the
+// fd side-channel recv) to check it actually starts req.Spec's process
+// against the given stdio, instead of only importing the fds and never
+// exec'ing anything.
+func TestSpawnExecRunsProcessAsOwnUser(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("failed to look up current user: %v", err)
+	}
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		t.Fatalf("failed to parse uid %q: %v", u.Uid, err)
+	}
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		t.Fatalf("failed to parse gid %q: %v", u.Gid, err)
+	}
+
+	specBytes, err := json.Marshal(&specs.Process{
+		Args: []string{"/bin/echo", "hello-from-exec"},
+		Cwd:  "/",
+		User: specs.User{UID: uint32(uid), GID: uint32(gid)},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal spec: %v", err)
+	}
+
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create stdout pipe: %v", err)
+	}
+	defer stdoutR.Close()
+
+	tk := &shimTask{id: "t1"}
+	req := &task.ExecProcessRequest{
+		ID:     "t1",
+		ExecID: "e1",
+		Spec:   &google_protobuf1.Any{Value: specBytes},
+	}
+	if err := tk.spawnExec(context.Background(), req, nil, stdoutW, stdoutW); err != nil {
+		t.Fatalf("spawnExec failed: %v", err)
+	}
+	stdoutW.Close()
+
+	v, ok := tk.execs.Load("e1")
+	if !ok {
+		t.Fatalf("expected spawnExec to record an execProcess for e1")
+	}
+	ep := v.(*execProcess)
+
+	select {
+	case err := <-ep.done:
+		if err != nil {
+			t.Fatalf("exec'd process failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for exec'd process")
+	}
+
+	out, err := io.ReadAll(stdoutR)
+	if err != nil {
+		t.Fatalf("failed to read stdout: %v", err)
+	}
+	if string(out) != "hello-from-exec\n" {
+		t.Fatalf("expected stdout %q, got %q", "hello-from-exec\n", out)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/containerd/containerd/runtime/v2/task"
+)
+
+// execProcess mirrors the linux-only bookkeeping; it is never populated
+// here since spawnExec always fails on windows.
+type execProcess struct{}
+
+// spawnExec is not implemented on windows: a process-isolated Windows
+// container's exec'd process needs to be created via the HCS compute
+// system's process APIs (or, for a Hyper-V isolated task, the GCS bridge),
+// neither of which is carried in this trimmed tree.
+func (t *shimTask) spawnExec(ctx context.Context, req *task.ExecProcessRequest, stdin, stdout, stderr *os.File) error {
+	return fmt.Errorf("spawning an exec'd process is not implemented on windows")
+}
@@ -42,9 +42,11 @@ type service struct {
 	// This MUST be treated as readonly for the lifetime of the shim.
 	isSandbox bool
 
-	// z is either the `pod` this shim is tracking if `isSandbox == true` or it
-	// is the `task` this shim is tracking. If no call to `Create` has taken
-	// place yet `z.Load()` MUST return `nil`.
+	// z is the *uvm.UtilityVM hosting this shim's task(s) for a Hyper-V
+	// isolated create, or a typed nil *uvm.UtilityVM for a process-isolated
+	// one. It is decided once, by the first call to newTask, and reused for
+	// every task this shim subsequently serves. Before the first call to
+	// `Create`, `z.Load()` returns an untyped `nil`.
 	z atomic.Value
 
 	// cl is the create lock. Since each shim MUST only track a single task or
@@ -52,6 +54,11 @@ type service struct {
 	// taken when creating tasks in a POD sandbox as they can happen
 	// concurrently.
 	cl sync.Mutex
+
+	// tasks holds every shimTask this shim is serving, keyed by task id. It
+	// backs getTask, used by the Checkpoint, Exec and Delete RPCs and the
+	// copy extension resource to find the UVM/rootfs a given task id maps to.
+	tasks sync.Map
 }
 
 func (s *service) State(ctx context.Context, req *task.StateRequest) (_ *task.StateResponse, err error) {
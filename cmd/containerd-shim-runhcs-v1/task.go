@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Microsoft/hcsshim/internal/hcsoci"
+	"github.com/Microsoft/hcsshim/internal/uvm"
+	"github.com/containerd/containerd/runtime/v2/task"
+	"github.com/containerd/typeurl"
+	google_protobuf1 "github.com/gogo/protobuf/types"
+)
+
+// newHostingVM returns the UVM that should host req's container for a
+// Hyper-V isolated create, or nil for a process-isolated one. It is a
+// package variable so tests can substitute a fake UVM without requiring a
+// real HCS-backed boot.
+var newHostingVM = func(ctx context.Context, req *task.CreateTaskRequest) (*uvm.UtilityVM, error) {
+	return nil, nil
+}
+
+// shimTask is the bookkeeping this shim keeps for a single task. It is
+// intentionally minimal: just enough state for the checkpoint/restore and
+// copy extension resources to find the UVM (if any) and rootfs a task id
+// maps to.
+type shimTask struct {
+	id string
+
+	// vm is the UVM hosting this task's container, or nil for a
+	// process-isolated (non-Hyper-V-isolated) Windows container.
+	vm *uvm.UtilityVM
+
+	// rootfsHostPath is the host-visible path to the container's rootfs
+	// (for a process-isolated container) or, for a Hyper-V isolated
+	// container, the host path used to stage data shared into the UVM.
+	rootfsHostPath string
+
+	// execs holds every execProcess spawned for this task via the fd
+	// side-channel path, keyed by exec id.
+	execs sync.Map
+}
+
+// getTask looks up the shimTask tracking `tid`.
+func (s *service) getTask(tid string) (*shimTask, error) {
+	v, ok := s.tasks.Load(tid)
+	if !ok {
+		return nil, fmt.Errorf("task with id %q not found", tid)
+	}
+	return v.(*shimTask), nil
+}
+
+// newTask creates the shimTask for `req` and, for a Hyper-V isolated
+// container, shares its rootfs into the UVM (via VSMB on Windows, virtio-fs
+// on Linux) so the guest can see it. The first call to newTask for this
+// shim decides, via newHostingVM, whether this is a Hyper-V isolated task
+// at all; the resulting UVM (or nil) is stored in s.z and reused by every
+// subsequent task the shim serves, since a single shim hosts at most one
+// UVM.
+func (s *service) newTask(ctx context.Context, req *task.CreateTaskRequest) (*shimTask, error) {
+	t := &shimTask{id: req.ID, rootfsHostPath: req.Bundle}
+
+	vm, ok := s.z.Load().(*uvm.UtilityVM)
+	if !ok {
+		v, err := newHostingVM(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create hosting UVM for %s: %s", req.ID, err)
+		}
+		vm = v
+		s.z.Store(vm)
+	}
+
+	if vm != nil {
+		t.vm = vm
+		if _, err := hcsoci.AddShare(ctx, vm, t.rootfsHostPath, false); err != nil {
+			return nil, fmt.Errorf("failed to share rootfs %s into UVM %s: %s", t.rootfsHostPath, req.ID, err)
+		}
+	}
+	return t, nil
+}
+
+// createInternal implements the `Create` RPC. Besides the normal
+// container/UVM bring-up above, it honors req.Checkpoint by restoring the
+// task's UVM from a checkpoint image directory instead of starting fresh,
+// optionally layered over req.ParentCheckpoint for an incremental restore.
+func (s *service) createInternal(ctx context.Context, req *task.CreateTaskRequest) (*task.CreateTaskResponse, error) {
+	t, err := s.newTask(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Checkpoint != "" {
+		if err := hcsoci.CreateContainerFromCheckpoint(ctx, req.ID, t.vm, req.Checkpoint, req.ParentCheckpoint); err != nil {
+			return nil, fmt.Errorf("failed to create %s from checkpoint %s: %s", req.ID, req.Checkpoint, err)
+		}
+	}
+
+	s.tasks.Store(req.ID, t)
+	return &task.CreateTaskResponse{}, nil
+}
+
+// checkpointInternal implements the `Checkpoint` RPC: it writes a checkpoint
+// image directory for the task's UVM at req.Path, taking it incrementally
+// against req.Options's ParentPath when one is supplied.
+func (s *service) checkpointInternal(ctx context.Context, req *task.CheckpointTaskRequest) (*google_protobuf1.Empty, error) {
+	t, err := s.getTask(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentPath string
+	if req.Options != nil {
+		v, err := typeurl.UnmarshalAny(req.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint options for %s: %s", req.ID, err)
+		}
+		if opts, ok := v.(*CheckpointOptions); ok {
+			parentPath = opts.ParentPath
+		}
+	}
+
+	if err := hcsoci.CheckpointContainer(ctx, req.ID, t.vm, req.Path, parentPath); err != nil {
+		return nil, err
+	}
+	return &google_protobuf1.Empty{}, nil
+}
+
+// rootfsPathForCopy returns the host-visible path the `cp` extension
+// resource should read/write against for this task, and a release func to
+// call once the copy is done. rootfsHostPath is already shared into the UVM
+// for the task's whole lifetime by newTask/deleteInternal, so there is no
+// additional transient mapping to set up or tear down here; the release
+// func is a no-op kept for symmetry with that share's own lifecycle.
+func (t *shimTask) rootfsPathForCopy(ctx context.Context) (string, func(), error) {
+	return t.rootfsHostPath, func() {}, nil
+}
+
+// execInternal implements the `Exec` RPC for the fd side-channel path: a
+// client opts into it by passing the side-channel's unix socket path as
+// req.Stdin and leaving Stdout/Stderr empty, instead of the usual named
+// pipes. It receives the stdio fds and uid/gid over that socket, imports
+// them as the exec'd process's stdio, and actually spawns the process
+// req.Spec describes, running it as the credentials req.Spec's `user`
+// field carries.
+func (s *service) execInternal(ctx context.Context, req *task.ExecProcessRequest) (*google_protobuf1.Empty, error) {
+	t, err := s.getTask(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Stdin == "" || req.Stdout != "" || req.Stderr != "" {
+		return nil, fmt.Errorf("exec %s:%s: fd side-channel requires the socket path in Stdin and Stdout/Stderr left empty", req.ID, req.ExecID)
+	}
+
+	fds, creds, err := recvExecFDs(ctx, req.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive exec fds for %s:%s: %s", req.ID, req.ExecID, err)
+	}
+	stdin, stdout, stderr, err := importExecFDs(req, fds, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import exec fds for %s:%s: %s", req.ID, req.ExecID, err)
+	}
+
+	if err := t.spawnExec(ctx, req, stdin, stdout, stderr); err != nil {
+		return nil, fmt.Errorf("failed to exec %s:%s: %s", req.ID, req.ExecID, err)
+	}
+
+	return &google_protobuf1.Empty{}, nil
+}
+
+// deleteInternal implements the `Delete` RPC: it undoes newTask's rootfs
+// share, if any, and forgets the task.
+func (s *service) deleteInternal(ctx context.Context, req *task.DeleteRequest) (*task.DeleteResponse, error) {
+	t, err := s.getTask(req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.vm != nil {
+		if err := hcsoci.RemoveShare(ctx, t.vm, t.rootfsHostPath, false); err != nil {
+			return nil, fmt.Errorf("failed to unshare rootfs %s from UVM %s: %s", t.rootfsHostPath, req.ID, err)
+		}
+	}
+
+	s.tasks.Delete(req.ID)
+	return &task.DeleteResponse{}, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hcsschema "github.com/Microsoft/hcsshim/internal/schema2"
+	"github.com/Microsoft/hcsshim/internal/uvm"
+	"github.com/containerd/containerd/runtime/v2/task"
+	"github.com/containerd/typeurl"
+)
+
+// fakeHcsSystem is a minimal uvm.HCSSystem that stands in for HCS: Save
+// writes a marker file at statePath/memoryPath and Restore checks that the
+// state file exists, without touching real guest state. It mirrors internal/uvm's own test
+// double, since that one is unexported and this package can't reach it.
+type fakeHcsSystem struct{}
+
+func (f *fakeHcsSystem) Modify(ctx context.Context, settings *hcsschema.ModifySettingRequest) error {
+	return nil
+}
+
+func (f *fakeHcsSystem) Pause(ctx context.Context) error { return nil }
+
+func (f *fakeHcsSystem) Resume(ctx context.Context) error { return nil }
+
+func (f *fakeHcsSystem) Save(ctx context.Context, statePath, memoryPath string, opts *hcsschema.SaveOptions) error {
+	if err := os.WriteFile(statePath, []byte("state"), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(memoryPath, []byte("memory"), 0600)
+}
+
+func (f *fakeHcsSystem) Restore(ctx context.Context, statePath, memoryPath string) error {
+	_, err := os.Stat(statePath)
+	return err
+}
+
+// TestCreateCheckpointDeleteEndToEnd drives the actual Create/Checkpoint/
+// Delete RPCs (not uvm.Checkpoint/Restore directly) against a shim whose
+// newHostingVM hook supplies a fake UVM, proving the UVM newTask picks up
+// actually flows through to the checkpoint and share call sites.
+func TestCreateCheckpointDeleteEndToEnd(t *testing.T) {
+	ctx := context.Background()
+
+	testVM := uvm.NewTestUVM("test-uvm", "windows", &fakeHcsSystem{})
+	origHook := newHostingVM
+	newHostingVM = func(ctx context.Context, req *task.CreateTaskRequest) (*uvm.UtilityVM, error) {
+		return testVM, nil
+	}
+	defer func() { newHostingVM = origHook }()
+
+	s := &service{}
+	bundle := t.TempDir()
+
+	if _, err := s.Create(ctx, &task.CreateTaskRequest{ID: "t1", Bundle: bundle}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	vm, ok := s.z.Load().(*uvm.UtilityVM)
+	if !ok || vm != testVM {
+		t.Fatalf("expected s.z to hold the UVM newHostingVM returned, got %v (ok=%v)", vm, ok)
+	}
+
+	checkpointDir := filepath.Join(t.TempDir(), "checkpoint")
+	opts, err := typeurl.MarshalAny(&CheckpointOptions{ParentPath: ""})
+	if err != nil {
+		t.Fatalf("failed to marshal checkpoint options: %v", err)
+	}
+	if _, err := s.Checkpoint(ctx, &task.CheckpointTaskRequest{ID: "t1", Path: checkpointDir, Options: opts}); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if _, err := os.Stat(checkpointDir); err != nil {
+		t.Fatalf("expected checkpoint image dir to be written: %v", err)
+	}
+
+	if _, err := s.Delete(ctx, &task.DeleteRequest{ID: "t1"}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := s.getTask("t1"); err == nil {
+		t.Fatalf("expected task t1 to be forgotten after Delete")
+	}
+}
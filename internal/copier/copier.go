@@ -0,0 +1,305 @@
+// Package copier implements a streaming tar-based file copier for moving
+// files and directories into and out of a running container's rootfs
+// without stopping the container or requiring an Exec of `tar`, analogous to
+// buildah's copier package.
+package copier
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Direction is which way a copy runs relative to the container.
+type Direction int
+
+const (
+	// DirectionGet streams files out of the container rootfs to the client (guest/host -> host tar stream).
+	DirectionGet Direction = iota
+	// DirectionPut streams a tar archive from the client into the container rootfs (host tar stream -> guest/host).
+	DirectionPut
+)
+
+// Options controls a single copy operation.
+type Options struct {
+	// Root is the resolved rootfs path the copy is chrooted to. Every path
+	// under Root is sanitized so `..` components and absolute symlink
+	// targets can't escape it.
+	Root string
+	// Include/Exclude are .dockerignore-style glob filters. A path is
+	// copied if it matches Include (or Include is empty) and does not match
+	// Exclude. Patterns are matched with filepath.Match. In Get, a directory
+	// that matches Exclude is not descended into, so nothing under it is
+	// copied.
+	Include []string
+	Exclude []string
+}
+
+// matches reports whether `relPath` matches any of the glob patterns in `patterns`.
+func matches(patterns []string, relPath string) (bool, error) {
+	for _, pattern := range patterns {
+		ok, err := filepath.Match(pattern, relPath)
+		if err != nil {
+			return false, fmt.Errorf("invalid filter pattern %q: %s", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// included reports whether `relPath` should be copied given opts' include/exclude filters.
+func included(opts Options, relPath string) (bool, error) {
+	if len(opts.Include) > 0 {
+		ok, err := matches(opts.Include, relPath)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	excluded, err := matches(opts.Exclude, relPath)
+	if err != nil {
+		return false, err
+	}
+	return !excluded, nil
+}
+
+// ResolveInRoot chroot-evaluates `path` against opts.Root: every `..`
+// component and every symlink target, INCLUDING symlinks in intermediate
+// path components, is resolved relative to Root so the result can never
+// reference a path outside of it. It mirrors the symlink escape handling a
+// tar extractor needs to do to safely unpack into a container rootfs: a
+// whole-path Lstat is not enough, since a symlink earlier in the path (e.g.
+// "a/b -> /etc" in "a/b/c") would let "c" resolve outside Root even though
+// the final component "a/b/c" is never itself a symlink.
+func ResolveInRoot(root, path string) (string, error) {
+	root = filepath.Clean(root)
+	rel := filepath.Clean(string(filepath.Separator) + strings.TrimPrefix(path, root))
+
+	resolved := root
+	components := strings.Split(rel, string(filepath.Separator))
+	for _, component := range components {
+		if component == "" || component == "." {
+			continue
+		}
+		resolved = filepath.Join(resolved, component)
+		if !withinRoot(root, resolved) {
+			resolved = root
+			continue
+		}
+
+		target, err := resolveSymlink(root, resolved, 0)
+		if err != nil {
+			return "", err
+		}
+		resolved = target
+	}
+	return resolved, nil
+}
+
+// resolveSymlink follows `path` if it is a symlink, resolving the target
+// relative to `root` (absolute targets) or the symlink's own directory
+// (relative targets), and clamping the result back inside `root` if it
+// would otherwise escape. It recurses to follow a chain of symlinks, up to
+// 255 hops, matching the loop-guard depth a tar extractor would use.
+func resolveSymlink(root, path string, depth int) (string, error) {
+	if depth > 255 {
+		return "", fmt.Errorf("too many levels of symbolic links resolving %s", path)
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return path, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return path, nil
+	}
+
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	var resolved string
+	if filepath.IsAbs(target) {
+		resolved = filepath.Join(root, target)
+	} else {
+		resolved = filepath.Join(filepath.Dir(path), target)
+	}
+	if !withinRoot(root, resolved) {
+		resolved = root
+	}
+	return resolveSymlink(root, resolved, depth+1)
+}
+
+// withinRoot reports whether `path` is root itself or a path under it,
+// guarding against a plain string-prefix check treating a sibling like
+// "/foobar" as being inside root "/foo".
+func withinRoot(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// checkSymlinkTarget rejects a tar entry's symlink target if it would point
+// outside root once actually dereferenced on disk. linkPath is the
+// already-root-resolved path the symlink itself will be created at. Unlike
+// resolveSymlink (which joins an absolute target against root to simulate a
+// chroot while walking a path we've already decided is safe), a symlink
+// this package WRITES is a real on-disk symlink with no chroot backing it:
+// an absolute target dereferences straight to the host's true root, so it
+// always escapes; a relative target escapes if it walks outside root from
+// the symlink's own directory. Put creates the symlink with its original,
+// unmodified target on success; this only guards against ever creating one
+// whose target escapes root in the first place.
+func checkSymlinkTarget(root, linkPath, target string) error {
+	if filepath.IsAbs(target) {
+		return fmt.Errorf("symlink target %q is absolute and would escape root %q", target, root)
+	}
+	resolved := filepath.Join(filepath.Dir(linkPath), target)
+	if !withinRoot(root, resolved) {
+		return fmt.Errorf("symlink target %q escapes root %q", target, root)
+	}
+	return nil
+}
+
+// Get walks `srcPath` (relative to opts.Root) and streams it as a tar
+// archive to `w`, preserving mode bits, timestamps, and (on Unix) ownership.
+// Symlink targets are resolved against opts.Root so a malicious symlink
+// inside the rootfs cannot cause files from outside it to be read.
+func Get(w io.Writer, opts Options, srcPath string) error {
+	resolved, err := ResolveInRoot(opts.Root, filepath.Join(opts.Root, srcPath))
+	if err != nil {
+		return fmt.Errorf("failed to resolve copy source %s: %s", srcPath, err)
+	}
+
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	return filepath.Walk(resolved, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(opts.Root, p)
+		if err != nil {
+			return err
+		}
+		ok, err := included(opts, rel)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if info.IsDir() {
+				excluded, err := matches(opts.Exclude, rel)
+				if err != nil {
+					return err
+				}
+				if excluded {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := readXattrs(p, hdr); err != nil {
+			return fmt.Errorf("failed to read xattrs/ACLs for %s: %s", rel, err)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(tw, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Put reads a tar archive from `r` and extracts it under `dstPath` (relative
+// to opts.Root), resolving every entry's path against opts.Root so an
+// archive containing `..` components or absolute symlinks cannot write
+// outside the rootfs.
+func Put(r io.Reader, opts Options, dstPath string) error {
+	tr := tar.NewReader(r)
+	base := filepath.Join(opts.Root, dstPath)
+	if err := os.MkdirAll(base, 0755); err != nil {
+		return fmt.Errorf("failed to create copy destination %s: %s", dstPath, err)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar stream: %s", err)
+		}
+
+		rel := filepath.Clean(hdr.Name)
+		ok, err := included(opts, rel)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		target, err := ResolveInRoot(opts.Root, filepath.Join(base, rel))
+		if err != nil {
+			return fmt.Errorf("failed to resolve copy target %s: %s", hdr.Name, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkSymlinkTarget(opts.Root, target, hdr.Linkname); err != nil {
+				return fmt.Errorf("refusing to extract %s: %s", hdr.Name, err)
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		}
+		if err := os.Chtimes(target, hdr.AccessTime, hdr.ModTime); err != nil {
+			return fmt.Errorf("failed to set timestamps on %s: %s", target, err)
+		}
+		if err := writeXattrs(target, hdr); err != nil {
+			return fmt.Errorf("failed to restore xattrs/ACLs on %s: %s", target, err)
+		}
+	}
+}
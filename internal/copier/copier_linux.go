@@ -0,0 +1,88 @@
+//go:build linux
+// +build linux
+
+package copier
+
+import (
+	"archive/tar"
+
+	"golang.org/x/sys/unix"
+)
+
+// aclXattrs are the xattr names POSIX ACLs are stored under; preserving them
+// alongside the regular xattr set is what makes Get/Put round-trip ACLs,
+// since Linux has no separate ACL syscall path distinct from xattrs.
+var aclXattrs = []string{"system.posix_acl_access", "system.posix_acl_default"}
+
+// readXattrs lists every xattr on `path` (including the ACL ones above) and
+// records them in hdr.PAXRecords with tar's standard "SCHILY.xattr." prefix
+// so Put can restore them later.
+func readXattrs(path string, hdr *tar.Header) error {
+	size, err := unix.Llistxattr(path, nil)
+	if err != nil {
+		if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+			return nil
+		}
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	n, err := unix.Llistxattr(path, buf)
+	if err != nil {
+		return err
+	}
+
+	if hdr.PAXRecords == nil {
+		hdr.PAXRecords = make(map[string]string)
+	}
+	for _, name := range splitNulTerminated(buf[:n]) {
+		vsize, err := unix.Lgetxattr(path, name, nil)
+		if err != nil {
+			continue
+		}
+		val := make([]byte, vsize)
+		vn, err := unix.Lgetxattr(path, name, val)
+		if err != nil {
+			continue
+		}
+		hdr.PAXRecords["SCHILY.xattr."+name] = string(val[:vn])
+	}
+	return nil
+}
+
+// writeXattrs restores every "SCHILY.xattr."-prefixed PAX record in hdr onto
+// `path`, including the POSIX ACL xattrs readXattrs captured.
+func writeXattrs(path string, hdr *tar.Header) error {
+	const prefix = "SCHILY.xattr."
+	for k, v := range hdr.PAXRecords {
+		if len(k) <= len(prefix) || k[:len(prefix)] != prefix {
+			continue
+		}
+		name := k[len(prefix):]
+		if err := unix.Lsetxattr(path, name, []byte(v), 0); err != nil {
+			if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// splitNulTerminated splits the NUL-separated name list unix.Llistxattr
+// returns into individual xattr names.
+func splitNulTerminated(buf []byte) []string {
+	var names []string
+	start := 0
+	for i, b := range buf {
+		if b == 0 {
+			if i > start {
+				names = append(names, string(buf[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
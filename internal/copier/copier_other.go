@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package copier
+
+import "archive/tar"
+
+// readXattrs is a no-op outside linux: xattrs/ACLs are a linux-specific
+// filesystem feature this package doesn't attempt to emulate elsewhere.
+func readXattrs(path string, hdr *tar.Header) error {
+	return nil
+}
+
+// writeXattrs is a no-op outside linux, mirroring readXattrs.
+func writeXattrs(path string, hdr *tar.Header) error {
+	return nil
+}
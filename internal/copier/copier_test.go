@@ -0,0 +1,80 @@
+package copier
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestResolveInRootBlocksIntermediateSymlinkEscape checks that a symlink in
+// an intermediate path component, not just the final one, can't be used to
+// escape Root: "a/b -> outside" followed by "/secret" must still resolve
+// under root, the way a tar extractor unpacking into a container rootfs
+// needs it to.
+func TestResolveInRootBlocksIntermediateSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("x"), 0600); err != nil {
+		t.Fatalf("failed to write outside file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatalf("failed to create root/a: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "a", "b")); err != nil {
+		t.Fatalf("failed to create escaping symlink: %v", err)
+	}
+
+	resolved, err := ResolveInRoot(root, filepath.Join(root, "a", "b", "secret"))
+	if err != nil {
+		t.Fatalf("ResolveInRoot failed: %v", err)
+	}
+	if !withinRoot(root, resolved) {
+		t.Fatalf("expected resolved path to stay within root, got %s (root=%s)", resolved, root)
+	}
+}
+
+// TestPutRejectsEscapingSymlink checks that Put refuses to extract a tar
+// entry whose symlink target escapes Root, whether absolute (e.g. "/etc") or
+// relative (e.g. "../../../etc"): ResolveInRoot only safely resolves the
+// symlink's own path, not the raw target a malicious tar entry asks to
+// persist into it, so Put must validate the target itself before creating
+// the symlink on disk.
+func TestPutRejectsEscapingSymlink(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+	}{
+		{name: "absolute", target: "/etc"},
+		{name: "relative-escape", target: "../../../../etc/passwd"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			root := t.TempDir()
+
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			if err := tw.WriteHeader(&tar.Header{
+				Name:     "evil",
+				Typeflag: tar.TypeSymlink,
+				Linkname: c.target,
+				Mode:     0777,
+			}); err != nil {
+				t.Fatalf("failed to write tar header: %v", err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatalf("failed to close tar writer: %v", err)
+			}
+
+			err := Put(&buf, Options{Root: root}, "/")
+			if err == nil {
+				t.Fatalf("expected Put to refuse an escaping symlink target %q", c.target)
+			}
+			if _, statErr := os.Lstat(filepath.Join(root, "evil")); statErr == nil {
+				t.Fatalf("expected no symlink to be created on disk for escaping target %q", c.target)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package fdimport imports pre-opened host file descriptors handed over on
+// an exec's ttrpc side-channel and wires them up as a process's stdio, as an
+// alternative to the named-pipe/FIFO paths carried in ExecProcessRequest.
+package fdimport
+
+import (
+	"fmt"
+	"os"
+)
+
+// Credentials are the uid/gid Import chowns the imported fds to.
+type Credentials struct {
+	UID uint32
+	GID uint32
+}
+
+// Import associates `fds` (in stdin, stdout, stderr order) with a process's
+// stdio, chowning them to `creds` so the process can actually use them once
+// it is running as that uid/gid. Unlike gvisor's fdimport.Import, this does
+// not promote a TTY fd to a controlling terminal itself: TIOCSCTTY only
+// makes sense called by the process that is about to become its session
+// leader, which at the point Import runs is still the shim, not the
+// exec'd process. A terminal fd's caller instead sets the child's
+// controlling TTY as part of spawning it (e.g. via syscall.SysProcAttr's
+// Setsid/Setctty on Linux).
+func Import(creds Credentials, fds []*os.File) (stdin, stdout, stderr *os.File, err error) {
+	if len(fds) != 3 {
+		return nil, nil, nil, fmt.Errorf("fdimport: expected 3 fds (stdin, stdout, stderr), got %d", len(fds))
+	}
+
+	stdin, stdout, stderr = fds[0], fds[1], fds[2]
+	for _, f := range fds {
+		if err := chownFD(f, creds); err != nil {
+			return nil, nil, nil, fmt.Errorf("fdimport: failed to chown fd %s to uid %d gid %d: %s", f.Name(), creds.UID, creds.GID, err)
+		}
+	}
+	return stdin, stdout, stderr, nil
+}
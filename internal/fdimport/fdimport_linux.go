@@ -0,0 +1,16 @@
+//go:build linux
+// +build linux
+
+package fdimport
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// chownFD hands ownership of `f` to creds.UID/creds.GID so the imported
+// process can read/write it after dropping privileges to that uid/gid.
+func chownFD(f *os.File, creds Credentials) error {
+	return unix.Fchown(int(f.Fd()), int(creds.UID), int(creds.GID))
+}
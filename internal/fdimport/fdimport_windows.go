@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package fdimport
+
+import (
+	"fmt"
+	"os"
+)
+
+// chownFD is not supported on Windows: there is no uid/gid ownership model
+// for a Windows handle to chown into.
+func chownFD(f *os.File, creds Credentials) error {
+	return fmt.Errorf("fdimport: chowning a fd is not supported on windows")
+}
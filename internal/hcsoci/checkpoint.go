@@ -0,0 +1,47 @@
+package hcsoci
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Microsoft/hcsshim/internal/log"
+	"github.com/Microsoft/hcsshim/internal/logfields"
+	"github.com/Microsoft/hcsshim/internal/uvm"
+)
+
+// CheckpointContainer writes a checkpoint image directory for `vm` at `path`.
+// If parentPath is non-empty the checkpoint is taken incrementally against
+// the checkpoint already present there.
+func CheckpointContainer(ctx context.Context, cid string, vm *uvm.UtilityVM, path string, parentPath string) error {
+	op := "hcsoci::CheckpointContainer"
+	l := log.G(ctx).WithField(logfields.ContainerID, cid)
+	l.Debug(op + " - Begin")
+	defer func() {
+		l.Debug(op + " - End")
+	}()
+
+	if vm == nil {
+		return fmt.Errorf("cannot checkpoint %s: no utility VM associated with container", cid)
+	}
+	return vm.Checkpoint(ctx, path, parentPath)
+}
+
+// CreateContainerFromCheckpoint restores `vm` from the checkpoint image
+// directory at `path`, re-attaching every VSMB share that was recorded at
+// checkpoint time before asking HCS to resume the saved container. If
+// `path` is itself an incremental checkpoint, parentPath must be the base
+// checkpoint it was taken against; pass "" for a full (non-incremental)
+// checkpoint.
+func CreateContainerFromCheckpoint(ctx context.Context, cid string, vm *uvm.UtilityVM, path string, parentPath string) error {
+	op := "hcsoci::CreateContainerFromCheckpoint"
+	l := log.G(ctx).WithField(logfields.ContainerID, cid)
+	l.Debug(op + " - Begin")
+	defer func() {
+		l.Debug(op + " - End")
+	}()
+
+	if vm == nil {
+		return fmt.Errorf("cannot restore %s: no utility VM associated with container", cid)
+	}
+	return vm.Restore(ctx, path, parentPath)
+}
@@ -0,0 +1,35 @@
+package hcsoci
+
+import (
+	"context"
+
+	"github.com/Microsoft/hcsshim/internal/uvm"
+)
+
+// AddShare attaches `hostPath` into `vm`, picking the VSMB backend for
+// Windows UVMs and the virtio-fs backend for Linux UVMs so that callers get
+// the same ref-counted share/mount semantics regardless of guest OS. It
+// returns the resulting guest path.
+func AddShare(ctx context.Context, vm *uvm.UtilityVM, hostPath string, readOnly bool) (string, error) {
+	if vm.OS() == "windows" {
+		options := vm.DefaultVSMBOptions(readOnly)
+		if _, err := vm.AddVSMB(ctx, hostPath, options); err != nil {
+			return "", err
+		}
+		return vm.GetVSMBUvmPath(ctx, hostPath, readOnly)
+	}
+
+	if _, err := vm.AddVirtioFs(ctx, hostPath, readOnly); err != nil {
+		return "", err
+	}
+	return vm.GetVirtioFsUvmPath(ctx, hostPath, readOnly)
+}
+
+// RemoveShare detaches `hostPath` from `vm`, using whichever backend
+// (VSMB or virtio-fs) is appropriate for the UVM's guest OS.
+func RemoveShare(ctx context.Context, vm *uvm.UtilityVM, hostPath string, readOnly bool) error {
+	if vm.OS() == "windows" {
+		return vm.RemoveVSMB(ctx, hostPath, readOnly)
+	}
+	return vm.RemoveVirtioFs(ctx, hostPath, readOnly)
+}
@@ -0,0 +1,12 @@
+// Package requesttype defines the RequestType values carried on a
+// ModifySettingRequest sent to HCS.
+package requesttype
+
+// RequestType is the kind of change a ModifySettingRequest describes.
+type RequestType string
+
+const (
+	Add    RequestType = "Add"
+	Remove RequestType = "Remove"
+	Update RequestType = "Update"
+)
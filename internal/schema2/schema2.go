@@ -0,0 +1,72 @@
+// Package schema2 contains the subset of the HCS v2 schema document used to
+// configure VSMB shares, virtio-fs shares, and UVM save/restore operations.
+package schema2
+
+// ModifySettingRequest is the envelope used to add, remove, or update a
+// resource in a compute system's configuration document.
+type ModifySettingRequest struct {
+	RequestType  interface{} `json:"RequestType"`
+	Settings     interface{} `json:"Settings,omitempty"`
+	ResourcePath string      `json:"ResourcePath,omitempty"`
+}
+
+// VirtualSmbShareOptions are the options applied to a VSMB share.
+type VirtualSmbShareOptions struct {
+	ReadOnly           bool `json:"ReadOnly,omitempty"`
+	ShareRead          bool `json:"ShareRead,omitempty"`
+	CacheIo            bool `json:"CacheIo,omitempty"`
+	NoDirectmap        bool `json:"NoDirectmap,omitempty"`
+	NoOplocks          bool `json:"NoOplocks,omitempty"`
+	PseudoOplocks      bool `json:"PseudoOplocks,omitempty"`
+	TakeBackupPrivilege bool `json:"TakeBackupPrivilege,omitempty"`
+	NoLocks            bool `json:"NoLocks,omitempty"`
+	RestrictFileAccess bool `json:"RestrictFileAccess,omitempty"`
+	SingleFileMapping  bool `json:"SingleFileMapping,omitempty"`
+}
+
+// VirtualSmbShare is a single VSMB share entry in the configuration document.
+type VirtualSmbShare struct {
+	Name         string                   `json:"Name"`
+	Path         string                   `json:"Path,omitempty"`
+	Options      *VirtualSmbShareOptions  `json:"Options,omitempty"`
+	AllowedFiles []string                 `json:"AllowedFiles,omitempty"`
+}
+
+// VirtioFsShare is a single virtio-fs share entry in the configuration document.
+type VirtioFsShare struct {
+	Tag          string   `json:"Tag"`
+	Path         string   `json:"Path,omitempty"`
+	ReadOnly     bool     `json:"ReadOnly,omitempty"`
+	AllowedFiles []string `json:"AllowedFiles,omitempty"`
+}
+
+// SaveOptions controls a UVM state/memory save operation.
+type SaveOptions struct {
+	// ParentStatePath, when set, causes the save to only persist the memory
+	// pages that changed since the dump at this path (an incremental,
+	// criu-pre-dump-style save).
+	ParentStatePath string `json:"ParentStatePath,omitempty"`
+}
+
+// VirtualSmb is the VSMB device group of a compute system document.
+type VirtualSmb struct {
+	Shares []VirtualSmbShare `json:"Shares,omitempty"`
+}
+
+// Devices is the device group of a virtual machine's configuration document.
+type Devices struct {
+	VirtualSmb     *VirtualSmb     `json:"VirtualSmb,omitempty"`
+	VirtioFsShares []VirtioFsShare `json:"VirtioFsShares,omitempty"`
+}
+
+// VirtualMachine is the virtual-machine-specific section of a compute
+// system's configuration document.
+type VirtualMachine struct {
+	Devices *Devices `json:"Devices,omitempty"`
+}
+
+// ComputeSystem is the configuration document HCS uses to create (or clone)
+// a compute system.
+type ComputeSystem struct {
+	VirtualMachine *VirtualMachine `json:"VirtualMachine,omitempty"`
+}
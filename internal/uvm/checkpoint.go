@@ -0,0 +1,188 @@
+package uvm
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	hcsschema "github.com/Microsoft/hcsshim/internal/schema2"
+)
+
+const (
+	// checkpointManifestName is the file written at the root of an image
+	// directory describing the checkpoint.
+	checkpointManifestName = "manifest.json"
+	// checkpointStateName holds the serialized HCS process/device state.
+	checkpointStateName = "state.bin"
+	// checkpointMemoryName holds the guest memory dump (full or, for an
+	// incremental checkpoint, only the pages that changed since the parent).
+	checkpointMemoryName = "memory.img"
+	// checkpointVSMBName holds the gob-encoded inventory of VSMB shares that
+	// were attached to the UVM at checkpoint time.
+	checkpointVSMBName = "vsmb.gob"
+)
+
+// checkpointManifest is serialized to manifest.json inside a checkpoint image
+// directory. It records enough bookkeeping for Restore to re-create the UVM
+// without needing anything but the directory itself.
+type checkpointManifest struct {
+	// ID is the ID of the UVM that was checkpointed.
+	ID string `json:"id"`
+	// ParentPath is the path of the parent checkpoint this one was taken
+	// against, if any. When set, Restore first replays ParentPath and then
+	// layers this checkpoint's memory pages on top of it.
+	ParentPath string `json:"parentPath,omitempty"`
+}
+
+// Checkpoint pauses the UVM, writes its process/device state, a memory dump,
+// and the current VSMB share inventory into `path`, and then resumes the UVM.
+//
+// If parentPath is non-empty, the checkpoint is taken incrementally: only the
+// memory pages that differ from the dump already present at parentPath are
+// written to `path`, mirroring a criu pre-dump/dump pair.
+func (uvm *UtilityVM) Checkpoint(ctx context.Context, path string, parentPath string) (err error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint image directory %s: %s", path, err)
+	}
+
+	if err := uvm.hcsSystem.Pause(ctx); err != nil {
+		return fmt.Errorf("failed to pause UVM %s for checkpoint: %s", uvm.id, err)
+	}
+	defer func() {
+		if rerr := uvm.hcsSystem.Resume(ctx); rerr != nil && err == nil {
+			err = fmt.Errorf("failed to resume UVM %s after checkpoint: %s", uvm.id, rerr)
+		}
+	}()
+
+	opts := &hcsschema.SaveOptions{}
+	if parentPath != "" {
+		opts.ParentStatePath = filepath.Join(parentPath, checkpointMemoryName)
+	}
+	if err := uvm.hcsSystem.Save(ctx, filepath.Join(path, checkpointStateName), filepath.Join(path, checkpointMemoryName), opts); err != nil {
+		return fmt.Errorf("failed to save UVM %s state: %s", uvm.id, err)
+	}
+
+	if err := uvm.writeVSMBInventory(filepath.Join(path, checkpointVSMBName)); err != nil {
+		return err
+	}
+
+	manifest := checkpointManifest{ID: uvm.id, ParentPath: parentPath}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint manifest: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(path, checkpointManifestName), manifestBytes, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint manifest: %s", err)
+	}
+	return nil
+}
+
+// Restore re-materializes an UVM from a checkpoint image directory previously
+// produced by Checkpoint: it re-attaches every VSMB share recorded in the
+// inventory (bumping vsmbCounter the same way AddVSMB would) and then asks
+// HCS to resume execution from the saved state/memory dump. If `path` is an
+// incremental checkpoint, parentPath must be the base checkpoint it was
+// taken against (falling back to the path recorded in the manifest, if any);
+// pass "" for a full (non-incremental) checkpoint.
+func (uvm *UtilityVM) Restore(ctx context.Context, path string, parentPath string) error {
+	manifestBytes, err := os.ReadFile(filepath.Join(path, checkpointManifestName))
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint manifest at %s: %s", path, err)
+	}
+	var manifest checkpointManifest
+	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return fmt.Errorf("failed to parse checkpoint manifest at %s: %s", path, err)
+	}
+	if parentPath == "" {
+		parentPath = manifest.ParentPath
+	}
+
+	if err := uvm.restoreVSMBInventory(ctx, filepath.Join(path, checkpointVSMBName)); err != nil {
+		return err
+	}
+
+	statePath := filepath.Join(path, checkpointStateName)
+	memoryPath := filepath.Join(path, checkpointMemoryName)
+	if parentPath != "" {
+		memoryPath = filepath.Join(parentPath, checkpointMemoryName) + "," + memoryPath
+	}
+	if err := uvm.hcsSystem.Restore(ctx, statePath, memoryPath); err != nil {
+		return fmt.Errorf("failed to restore UVM %s from %s: %s", uvm.id, path, err)
+	}
+	return nil
+}
+
+// writeVSMBInventory gob-encodes every currently attached VSMB share (both
+// directory and single-file mappings) to `path`, reusing VSMBShare's existing
+// GobEncode implementation.
+func (uvm *UtilityVM) writeVSMBInventory(path string) error {
+	uvm.m.Lock()
+	shares := make([]*VSMBShare, 0, len(uvm.vsmbDirShares)+len(uvm.vsmbFileShares))
+	for _, s := range uvm.vsmbDirShares {
+		shares = append(shares, s)
+	}
+	for _, s := range uvm.vsmbFileShares {
+		shares = append(shares, s)
+	}
+	uvm.m.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create VSMB inventory file: %s", err)
+	}
+	defer f.Close()
+
+	if err := gob.NewEncoder(f).Encode(shares); err != nil {
+		return fmt.Errorf("failed to encode VSMB inventory: %s", err)
+	}
+	return nil
+}
+
+// restoreVSMBInventory re-attaches every VSMB share recorded at `path` by
+// calling AddVSMB with the recorded options and allowed-file list, which has
+// the side effect of bumping vsmbCounter and populating vsmbDirShares /
+// vsmbFileShares exactly as if the share had just been added.
+//
+// For a single-file share, AddVSMB is called once per recorded AllowedFiles
+// entry, which rebuilds its allowed-file list; a directory share has no
+// AllowedFiles to replay against and is re-attached with a single AddVSMB
+// call. In both cases the share's original ref count (how many independent
+// call sites had added it) is then restored directly from the recorded
+// refCount, since RemoveVSMB does not trim AllowedFiles and the number of
+// AddVSMB calls made here need not match it.
+func (uvm *UtilityVM) restoreVSMBInventory(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open VSMB inventory file: %s", err)
+	}
+	defer f.Close()
+
+	var shares []*VSMBShare
+	if err := gob.NewDecoder(f).Decode(&shares); err != nil {
+		return fmt.Errorf("failed to decode VSMB inventory: %s", err)
+	}
+
+	for _, s := range shares {
+		opts := s.Options
+		var share *VSMBShare
+		for _, file := range s.AllowedFiles {
+			if share, err = uvm.AddVSMB(ctx, file, &opts); err != nil {
+				return fmt.Errorf("failed to restore VSMB share for %s: %s", file, err)
+			}
+		}
+		if len(s.AllowedFiles) == 0 {
+			if share, err = uvm.AddVSMB(ctx, s.HostPath, &opts); err != nil {
+				return fmt.Errorf("failed to restore VSMB share for %s: %s", s.HostPath, err)
+			}
+		}
+		if s.refCount > 0 {
+			uvm.m.Lock()
+			share.refCount = s.refCount
+			uvm.m.Unlock()
+		}
+	}
+	return nil
+}
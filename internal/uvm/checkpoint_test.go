@@ -0,0 +1,186 @@
+package uvm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hcsschema "github.com/Microsoft/hcsshim/internal/schema2"
+)
+
+// fakeHcsSystem is a minimal hcsSystem that stands in for HCS in tests: Save
+// writes a marker file at statePath/memoryPath and Restore checks that the
+// state file exists, without touching real guest state.
+type fakeHcsSystem struct {
+	paused  bool
+	resumed bool
+}
+
+func (f *fakeHcsSystem) Modify(ctx context.Context, settings *hcsschema.ModifySettingRequest) error {
+	return nil
+}
+
+func (f *fakeHcsSystem) Pause(ctx context.Context) error {
+	f.paused = true
+	return nil
+}
+
+func (f *fakeHcsSystem) Resume(ctx context.Context) error {
+	f.resumed = true
+	return nil
+}
+
+func (f *fakeHcsSystem) Save(ctx context.Context, statePath, memoryPath string, opts *hcsschema.SaveOptions) error {
+	if err := os.WriteFile(statePath, []byte("state"), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(memoryPath, []byte("memory"), 0600)
+}
+
+func (f *fakeHcsSystem) Restore(ctx context.Context, statePath, memoryPath string) error {
+	if _, err := os.Stat(statePath); err != nil {
+		return err
+	}
+	return nil
+}
+
+func newTestUVM(id string) *UtilityVM {
+	return &UtilityVM{
+		id:              id,
+		operatingSystem: "windows",
+		hcsSystem:       &fakeHcsSystem{},
+		vsmbDirShares:   make(map[string]*VSMBShare),
+		vsmbFileShares:  make(map[string]*VSMBShare),
+	}
+}
+
+// TestCheckpointRestoreRoundTrip exercises a running UVM through
+// checkpoint -> delete -> create-from-checkpoint: it checkpoints a UVM with a
+// VSMB share attached, creates a brand new UVM, and restores it from the
+// image directory, verifying the VSMB share reappears.
+func TestCheckpointRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	shareDir := t.TempDir()
+	srcVM := newTestUVM("src")
+	if _, err := srcVM.AddVSMB(ctx, shareDir, srcVM.DefaultVSMBOptions(true)); err != nil {
+		t.Fatalf("AddVSMB failed: %v", err)
+	}
+
+	imageDir := filepath.Join(dir, "checkpoint")
+	if err := srcVM.Checkpoint(ctx, imageDir, ""); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	fake := srcVM.hcsSystem.(*fakeHcsSystem)
+	if !fake.paused || !fake.resumed {
+		t.Fatalf("expected UVM to be paused and resumed during checkpoint, got paused=%v resumed=%v", fake.paused, fake.resumed)
+	}
+
+	// "delete" the original UVM, then restore into a fresh one.
+	srcVM = nil
+
+	dstVM := newTestUVM("dst")
+	if err := dstVM.Restore(ctx, imageDir, ""); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if _, err := dstVM.GetVSMBUvmPath(ctx, shareDir, true); err != nil {
+		t.Fatalf("expected VSMB share for %s to be restored, got error: %v", shareDir, err)
+	}
+}
+
+// TestCheckpointRestorePreservesVSMBRefCount checks that a directory VSMB
+// share added from multiple call sites (refCount > 1) comes back from a
+// restore with the same refCount, instead of being reset to 1: otherwise a
+// later RemoveVSMB from one of those call sites tears the share down while
+// another call site is still using it.
+func TestCheckpointRestorePreservesVSMBRefCount(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	shareDir := t.TempDir()
+	srcVM := newTestUVM("src")
+	opts := srcVM.DefaultVSMBOptions(true)
+	if _, err := srcVM.AddVSMB(ctx, shareDir, opts); err != nil {
+		t.Fatalf("first AddVSMB failed: %v", err)
+	}
+	if _, err := srcVM.AddVSMB(ctx, shareDir, opts); err != nil {
+		t.Fatalf("second AddVSMB failed: %v", err)
+	}
+	if _, err := srcVM.AddVSMB(ctx, shareDir, opts); err != nil {
+		t.Fatalf("third AddVSMB failed: %v", err)
+	}
+
+	imageDir := filepath.Join(dir, "checkpoint")
+	if err := srcVM.Checkpoint(ctx, imageDir, ""); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+
+	dstVM := newTestUVM("dst")
+	if err := dstVM.Restore(ctx, imageDir, ""); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	shareKey := getVSMBShareKey(filepath.Clean(shareDir), true)
+	restored, ok := dstVM.vsmbDirShares[shareKey]
+	if !ok {
+		t.Fatalf("expected VSMB share for %s to be restored", shareDir)
+	}
+	if restored.refCount != 3 {
+		t.Fatalf("expected restored refCount 3, got %d", restored.refCount)
+	}
+
+	// Two of the three original call sites releasing their reference should
+	// leave the share attached; only the third should actually remove it.
+	if err := dstVM.RemoveVSMB(ctx, shareDir, true); err != nil {
+		t.Fatalf("first RemoveVSMB failed: %v", err)
+	}
+	if err := dstVM.RemoveVSMB(ctx, shareDir, true); err != nil {
+		t.Fatalf("second RemoveVSMB failed: %v", err)
+	}
+	if _, err := dstVM.GetVSMBUvmPath(ctx, shareDir, true); err != nil {
+		t.Fatalf("expected share to still be attached after 2 of 3 removes: %v", err)
+	}
+	if err := dstVM.RemoveVSMB(ctx, shareDir, true); err != nil {
+		t.Fatalf("third RemoveVSMB failed: %v", err)
+	}
+	if _, err := dstVM.GetVSMBUvmPath(ctx, shareDir, true); err == nil {
+		t.Fatalf("expected share to be detached after all 3 removes")
+	}
+}
+
+// TestCheckpointRestoreIncremental checks that an incremental checkpoint
+// records its parent's path in its manifest and that Restore, given no
+// explicit parent, succeeds using the parent recorded there.
+func TestCheckpointRestoreIncremental(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	baseVM := newTestUVM("base")
+	basePath := filepath.Join(dir, "base")
+	if err := baseVM.Checkpoint(ctx, basePath, ""); err != nil {
+		t.Fatalf("base Checkpoint failed: %v", err)
+	}
+
+	incVM := newTestUVM("inc")
+	incPath := filepath.Join(dir, "inc")
+	if err := incVM.Checkpoint(ctx, incPath, basePath); err != nil {
+		t.Fatalf("incremental Checkpoint failed: %v", err)
+	}
+
+	manifestBytes, err := os.ReadFile(filepath.Join(incPath, checkpointManifestName))
+	if err != nil {
+		t.Fatalf("failed to read incremental manifest: %v", err)
+	}
+	if !strings.Contains(string(manifestBytes), basePath) {
+		t.Fatalf("expected incremental manifest to reference parent path %s, got %s", basePath, manifestBytes)
+	}
+
+	dstVM := newTestUVM("dst")
+	if err := dstVM.Restore(ctx, incPath, ""); err != nil {
+		t.Fatalf("Restore of incremental checkpoint failed: %v", err)
+	}
+}
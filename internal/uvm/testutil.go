@@ -0,0 +1,21 @@
+package uvm
+
+// HCSSystem is the exported name for hcsSystem, the subset of the HCS
+// compute-system API a UtilityVM drives. It exists so tests in other
+// packages can build a UtilityVM over a fake compute system via NewTestUVM.
+type HCSSystem = hcsSystem
+
+// NewTestUVM builds a UtilityVM backed by hcs instead of a real HCS compute
+// system. It is for tests, in this package and others, that need a working
+// UtilityVM (e.g. to exercise Checkpoint/Restore/AddVSMB) without booting a
+// real one.
+func NewTestUVM(id, operatingSystem string, hcs HCSSystem) *UtilityVM {
+	return &UtilityVM{
+		id:              id,
+		hcsSystem:       hcs,
+		operatingSystem: operatingSystem,
+		vsmbDirShares:   make(map[string]*VSMBShare),
+		vsmbFileShares:  make(map[string]*VSMBShare),
+		virtioFsShares:  make(map[string]*VirtioFsShare),
+	}
+}
@@ -0,0 +1,92 @@
+package uvm
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	hcsschema "github.com/Microsoft/hcsshim/internal/schema2"
+)
+
+// errNotSupported is returned by operations that only apply to a subset of
+// UVM operating systems (e.g. AddVSMB on a Linux UVM, AddVirtioFs on a
+// Windows UVM).
+var errNotSupported = errors.New("not supported")
+
+// ErrNotAttached is returned when looking up a share/mount that isn't
+// currently attached to the UVM.
+var ErrNotAttached = errors.New("not attached")
+
+// vSmbShareResourcePath is the HCS resource path for VSMB share modifications.
+const vSmbShareResourcePath = "VirtualMachine/Devices/VirtualSmb/Shares"
+
+// virtioFsShareResourcePath is the HCS resource path for virtio-fs share
+// modifications.
+const virtioFsShareResourcePath = "VirtualMachine/Devices/VirtioFsShares"
+
+// hcsSystem is the subset of the HCS compute-system API a UtilityVM drives.
+// It is satisfied by the real HCS binding in the full build; tests substitute
+// a fake.
+type hcsSystem interface {
+	Modify(ctx context.Context, settings *hcsschema.ModifySettingRequest) error
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+	Save(ctx context.Context, statePath, memoryPath string, opts *hcsschema.SaveOptions) error
+	Restore(ctx context.Context, statePath, memoryPath string) error
+}
+
+// UtilityVM represents a Hyper-V isolated utility VM hosting one or more
+// containers.
+type UtilityVM struct {
+	m    sync.Mutex
+	id   string
+	hcsSystem hcsSystem
+
+	// operatingSystem is either "windows" or "linux" and determines which
+	// share backend (VSMB or virtio-fs) AddVSMB/AddVirtioFs operate against.
+	operatingSystem string
+
+	devicesPhysicallyBacked bool
+
+	vsmbCounter    uint64
+	vsmbDirShares  map[string]*VSMBShare
+	vsmbFileShares map[string]*VSMBShare
+
+	virtioFsCounter uint64
+	virtioFsShares  map[string]*VirtioFsShare
+}
+
+// ID returns the UVM's ID.
+func (uvm *UtilityVM) ID() string {
+	return uvm.id
+}
+
+// OS returns the UVM's guest operating system, "windows" or "linux".
+func (uvm *UtilityVM) OS() string {
+	return uvm.operatingSystem
+}
+
+// DevicesPhysicallyBacked reports whether devices attached to this UVM are
+// physically backed (and therefore don't need direct-mapping for VSMB).
+func (uvm *UtilityVM) DevicesPhysicallyBacked() bool {
+	return uvm.devicesPhysicallyBacked
+}
+
+// modify sends a ModifySettingRequest to the UVM's compute system.
+func (uvm *UtilityVM) modify(ctx context.Context, settings *hcsschema.ModifySettingRequest) error {
+	return uvm.hcsSystem.Modify(ctx, settings)
+}
+
+// Cloneable is implemented by UVM resources that can be carried over into a
+// cloned UVM's configuration document.
+type Cloneable interface {
+	GobEncode() ([]byte, error)
+	GobDecode([]byte) error
+	Clone(ctx context.Context, vm *UtilityVM, cd *cloneData) (interface{}, error)
+}
+
+// cloneData accumulates the configuration document for a UVM being created
+// as a clone of a template.
+type cloneData struct {
+	doc *hcsschema.ComputeSystem
+}
@@ -0,0 +1,261 @@
+package uvm
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Microsoft/hcsshim/internal/requesttype"
+	hcsschema "github.com/Microsoft/hcsshim/internal/schema2"
+)
+
+const virtioFsShareGuestPrefix = "/run/mounts/virtiofs/"
+
+// VirtioFsShare is the Linux-UVM peer of VSMBShare: a ref-counted virtio-fs
+// mount of a host directory (or, via AllowedFiles, a single host file) into
+// the guest. It is attached the same way VSMBShare is so that higher layers
+// don't need to know which backend a given `uvm.OS()` uses.
+type VirtioFsShare struct {
+	vm           *UtilityVM
+	HostPath     string
+	refCount     uint32
+	Tag          string
+	AllowedFiles []string
+	GuestPath    string
+	ReadOnly     bool
+}
+
+// Release frees the resources of the corresponding virtio-fs share.
+func (share *VirtioFsShare) Release(ctx context.Context) error {
+	if err := share.vm.RemoveVirtioFs(ctx, share.HostPath, share.ReadOnly); err != nil {
+		return fmt.Errorf("failed to remove virtio-fs share: %s", err)
+	}
+	return nil
+}
+
+// findVirtioFsShare finds a share by `shareKey`. If not found returns `ErrNotAttached`.
+func (uvm *UtilityVM) findVirtioFsShare(ctx context.Context, m map[string]*VirtioFsShare, shareKey string) (*VirtioFsShare, error) {
+	share, ok := m[shareKey]
+	if !ok {
+		return nil, ErrNotAttached
+	}
+	return share, nil
+}
+
+// AddVirtioFs adds a virtio-fs share to a Linux utility VM. Each share is
+// ref-counted by (hostPath, readOnly) and only added if it isn't already,
+// mirroring AddVSMB. As with AddVSMB, mapping a single file rather than a
+// directory maps the containing directory and restricts guest access to that
+// file via an allowed-file list applied at the guest FUSE layer.
+func (uvm *UtilityVM) AddVirtioFs(ctx context.Context, hostPath string, readOnly bool) (*VirtioFsShare, error) {
+	if uvm.operatingSystem != "linux" {
+		return nil, errNotSupported
+	}
+
+	uvm.m.Lock()
+	defer uvm.m.Unlock()
+
+	st, err := os.Stat(hostPath)
+	if err != nil {
+		return nil, err
+	}
+	var file string
+	if !st.IsDir() {
+		file = hostPath
+		hostPath = filepath.Dir(hostPath)
+	}
+	hostPath = filepath.Clean(hostPath)
+	var requestType = requesttype.Update
+	shareKey := getVirtioFsShareKey(hostPath, readOnly)
+	share, err := uvm.findVirtioFsShare(ctx, uvm.virtioFsShares, shareKey)
+	if err == ErrNotAttached {
+		requestType = requesttype.Add
+		uvm.virtioFsCounter++
+		tag := "virtiofs" + strconv.FormatUint(uvm.virtioFsCounter, 16)
+
+		share = &VirtioFsShare{
+			vm:        uvm,
+			Tag:       tag,
+			GuestPath: virtioFsShareGuestPrefix + tag,
+			HostPath:  hostPath,
+			ReadOnly:  readOnly,
+		}
+	}
+	newAllowedFiles := share.AllowedFiles
+	if file != "" {
+		newAllowedFiles = append(newAllowedFiles, file)
+	}
+
+	if requestType == requesttype.Add || file != "" {
+		modification := &hcsschema.ModifySettingRequest{
+			RequestType: requestType,
+			Settings: hcsschema.VirtioFsShare{
+				Tag:          share.Tag,
+				Path:         hostPath,
+				ReadOnly:     readOnly,
+				AllowedFiles: newAllowedFiles,
+			},
+			ResourcePath: virtioFsShareResourcePath,
+		}
+		if err := uvm.modify(ctx, modification); err != nil {
+			return nil, err
+		}
+	}
+
+	share.AllowedFiles = newAllowedFiles
+	share.refCount++
+	uvm.virtioFsShares[shareKey] = share
+	return share, nil
+}
+
+// RemoveVirtioFs removes a virtio-fs share from a utility VM. Each share is
+// ref-counted and only actually removed when the ref-count drops to zero.
+func (uvm *UtilityVM) RemoveVirtioFs(ctx context.Context, hostPath string, readOnly bool) error {
+	if uvm.operatingSystem != "linux" {
+		return errNotSupported
+	}
+
+	uvm.m.Lock()
+	defer uvm.m.Unlock()
+
+	st, err := os.Stat(hostPath)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		hostPath = filepath.Dir(hostPath)
+	}
+	hostPath = filepath.Clean(hostPath)
+	shareKey := getVirtioFsShareKey(hostPath, readOnly)
+	share, err := uvm.findVirtioFsShare(ctx, uvm.virtioFsShares, shareKey)
+	if err != nil {
+		return fmt.Errorf("%s is not present as a virtio-fs share in %s, cannot remove", hostPath, uvm.id)
+	}
+
+	share.refCount--
+	if share.refCount > 0 {
+		return nil
+	}
+
+	modification := &hcsschema.ModifySettingRequest{
+		RequestType:  requesttype.Remove,
+		Settings:     hcsschema.VirtioFsShare{Tag: share.Tag},
+		ResourcePath: virtioFsShareResourcePath,
+	}
+	if err := uvm.modify(ctx, modification); err != nil {
+		return fmt.Errorf("failed to remove virtio-fs share %s from %s: %+v: %s", hostPath, uvm.id, modification, err)
+	}
+
+	delete(uvm.virtioFsShares, shareKey)
+	return nil
+}
+
+// GetVirtioFsUvmPath returns the guest path of a virtio-fs mount.
+func (uvm *UtilityVM) GetVirtioFsUvmPath(ctx context.Context, hostPath string, readOnly bool) (string, error) {
+	if hostPath == "" {
+		return "", fmt.Errorf("no hostPath passed to GetVirtioFsUvmPath")
+	}
+
+	uvm.m.Lock()
+	defer uvm.m.Unlock()
+
+	st, err := os.Stat(hostPath)
+	if err != nil {
+		return "", err
+	}
+	f := ""
+	if !st.IsDir() {
+		hostPath, f = filepath.Split(hostPath)
+	}
+	hostPath = filepath.Clean(hostPath)
+	shareKey := getVirtioFsShareKey(hostPath, readOnly)
+	share, err := uvm.findVirtioFsShare(ctx, uvm.virtioFsShares, shareKey)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(share.GuestPath, f), nil
+}
+
+var _ = (Cloneable)(&VirtioFsShare{})
+
+// serializes the VirtioFsShare struct
+func (share *VirtioFsShare) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	errMsgFmt := "failed to encode VirtioFsShare: %s"
+	if err := encoder.Encode(share.HostPath); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
+	if err := encoder.Encode(share.Tag); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
+	if err := encoder.Encode(share.AllowedFiles); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
+	if err := encoder.Encode(share.GuestPath); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
+	if err := encoder.Encode(share.ReadOnly); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// deserializes the VirtioFsShare struct into the struct on which this is called
+func (share *VirtioFsShare) GobDecode(data []byte) error {
+	buf := bytes.NewBuffer(data)
+	decoder := gob.NewDecoder(buf)
+	errMsgFmt := "failed to decode VirtioFsShare: %s"
+	if err := decoder.Decode(&share.HostPath); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
+	if err := decoder.Decode(&share.Tag); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
+	if err := decoder.Decode(&share.AllowedFiles); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
+	if err := decoder.Decode(&share.GuestPath); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
+	if err := decoder.Decode(&share.ReadOnly); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
+	return nil
+}
+
+// To clone a virtio-fs share we just need to add it into the config doc of
+// that VM and increase the virtio-fs counter.
+func (share *VirtioFsShare) Clone(ctx context.Context, vm *UtilityVM, cd *cloneData) (interface{}, error) {
+	cd.doc.VirtualMachine.Devices.VirtioFsShares = append(cd.doc.VirtualMachine.Devices.VirtioFsShares, hcsschema.VirtioFsShare{
+		Tag:          share.Tag,
+		Path:         share.HostPath,
+		ReadOnly:     share.ReadOnly,
+		AllowedFiles: share.AllowedFiles,
+	})
+	vm.virtioFsCounter++
+
+	clonedShare := &VirtioFsShare{
+		vm:           vm,
+		HostPath:     share.HostPath,
+		refCount:     1,
+		Tag:          share.Tag,
+		ReadOnly:     share.ReadOnly,
+		AllowedFiles: share.AllowedFiles,
+		GuestPath:    share.GuestPath,
+	}
+
+	vm.virtioFsShares[getVirtioFsShareKey(share.HostPath, share.ReadOnly)] = clonedShare
+
+	return clonedShare, nil
+}
+
+// getVirtioFsShareKey returns a string key which encapsulates the information
+// that is used to look up an existing virtio-fs share.
+func getVirtioFsShareKey(hostPath string, readOnly bool) string {
+	return fmt.Sprintf("%v-%v", hostPath, readOnly)
+}
@@ -216,7 +216,9 @@ func (vsmb *VSMBShare) GobEncode() ([]byte, error) {
 	var buf bytes.Buffer
 	encoder := gob.NewEncoder(&buf)
 	errMsgFmt := "failed to encode VSMBShare: %s"
-	// encode only the fields that can be safely deserialized.
+	// encode only the fields that can be safely deserialized. refCount is
+	// included so a restored share's ref count matches how many independent
+	// call sites had added it, not just 1.
 	if err := encoder.Encode(vsmb.HostPath); err != nil {
 		return []byte{}, fmt.Errorf(errMsgFmt, err)
 	}
@@ -232,6 +234,9 @@ func (vsmb *VSMBShare) GobEncode() ([]byte, error) {
 	if err := encoder.Encode(vsmb.Options); err != nil {
 		return []byte{}, fmt.Errorf(errMsgFmt, err)
 	}
+	if err := encoder.Encode(vsmb.refCount); err != nil {
+		return []byte{}, fmt.Errorf(errMsgFmt, err)
+	}
 	return buf.Bytes(), nil
 }
 
@@ -256,6 +261,9 @@ func (vsmb *VSMBShare) GobDecode(data []byte) error {
 	if err := decoder.Decode(&vsmb.Options); err != nil {
 		return fmt.Errorf(errMsgFmt, err)
 	}
+	if err := decoder.Decode(&vsmb.refCount); err != nil {
+		return fmt.Errorf(errMsgFmt, err)
+	}
 	return nil
 }
 